@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// extractDirName is the top-level directory auto-extracted bundles land in:
+// <outDir>/extracted/<platform>/.
+const extractDirName = "extracted"
+
+// extractDownloadedBundles extracts every recognized platform bundle in
+// files, which is expected to hold file names just downloaded into outDir.
+func extractDownloadedBundles(files []string, outDir string) {
+	for _, file := range files {
+		info := parseBundleFileName(file)
+		if info.os == "" {
+			continue
+		}
+		path := filepath.Join(outDir, file)
+		if err := extractBundle(path, outDir, info.os); err != nil {
+			log.Printf("Failed to extract %s: %v\n", path, err)
+		}
+	}
+}
+
+// extractBundle unpacks the archive at path into <outDir>/extracted/<platform>,
+// dispatching on file extension. Installer formats with no portable
+// extraction path (.dmg, .exe) are left alone.
+func extractBundle(path, outDir, platform string) error {
+	destDir := filepath.Join(outDir, extractDirName, platform)
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create extraction directory %s: %v", destDir, err)
+	}
+
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"):
+		return extractTarGz(path, destDir)
+	case strings.HasSuffix(path, ".tar.xz"):
+		return extractTarXz(path, destDir)
+	case strings.HasSuffix(path, ".zip"):
+		return extractZip(path, destDir)
+	case strings.HasSuffix(path, ".dmg"), strings.HasSuffix(path, ".exe"):
+		log.Printf("Not extracting %s: no portable extraction path for this format\n", path)
+		return nil
+	default:
+		return nil
+	}
+}
+
+// extractTarGz unpacks a gzip-compressed tarball, the format Linux releases
+// ship in, reusing the archive/tar + compress/gzip pairing the Go toolchain
+// installer uses for its own .tar.gz archives.
+func extractTarGz(path, destDir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip header of %s: %v", path, err)
+	}
+	defer gz.Close()
+
+	return extractTar(tar.NewReader(gz), destDir)
+}
+
+// extractTarXz unpacks an xz-compressed tarball by shelling out to the
+// system tar, since there is no xz decompressor in the standard library.
+func extractTarXz(path, destDir string) error {
+	cmd := exec.Command("tar", "-xJf", path, "-C", destDir)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to extract %s: %v", path, err)
+	}
+	return nil
+}
+
+// extractTar writes every regular file and directory read from r into destDir.
+func extractTar(r *tar.Reader, destDir string) error {
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %v", err)
+		}
+
+		target, err := safeExtractPath(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, r)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractZip unpacks a zip archive, the format Windows and Android releases
+// ship in.
+func extractZip(path, destDir string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		target, err := safeExtractPath(destDir, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+
+		src, err := f.Open()
+		if err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
+		if err != nil {
+			src.Close()
+			return err
+		}
+		_, err = io.Copy(out, src)
+		src.Close()
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// safeExtractPath joins name onto destDir and rejects the result if name
+// (e.g. via "../" components) would escape destDir, the "zip slip" path
+// traversal that archive extraction code has to guard against.
+func safeExtractPath(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, name)
+	if target != destDir && !strings.HasPrefix(target, destDir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal file path %q in archive", name)
+	}
+	return target, nil
+}
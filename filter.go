@@ -0,0 +1,124 @@
+package main
+
+import "strings"
+
+// bundleInfo is the platform, architecture and locale parsed out of a Tor
+// Browser release file name, following the
+// tor-browser-<os>-<arch>-<version>_<locale>.<ext> convention (locale is
+// absent from Android APKs and from signature/manifest side-files).
+type bundleInfo struct {
+	os     string // "linux", "windows", "macos", "android", or "" if not a platform bundle.
+	arch   string // "x86_64", "aarch64", "i686", etc.
+	locale string // "en-US", "ALL" for unlocalized bundles, or "" if not a platform bundle.
+}
+
+// bundleOSPrefixes maps the file name prefix Tor Project uses for each
+// platform to the --os value that selects it.
+var bundleOSPrefixes = map[string]string{
+	"tor-browser-linux-":   "linux",
+	"tor-browser-windows-": "windows",
+	"tor-browser-macos-":   "macos",
+	"tor-browser-android-": "android",
+}
+
+// parseBundleFileName extracts the platform, architecture and locale encoded
+// in a release file name. It returns a zero bundleInfo for files that aren't
+// platform bundles, e.g. the checksum manifest or its signature.
+func parseBundleFileName(fileName string) bundleInfo {
+	var info bundleInfo
+
+	base := fileName
+	for prefix, os := range bundleOSPrefixes {
+		if strings.HasPrefix(base, prefix) {
+			info.os = os
+			base = strings.TrimPrefix(base, prefix)
+			break
+		}
+	}
+	if info.os == "" {
+		return info
+	}
+
+	// base is now "<arch>-<version>[_<locale>].<ext>..."
+	dash := strings.Index(base, "-")
+	if dash < 0 {
+		return info
+	}
+	info.arch = base[:dash]
+	rest := base[dash+1:]
+
+	underscore := strings.Index(rest, "_")
+	if underscore < 0 {
+		info.locale = "ALL"
+		return info
+	}
+
+	locale := rest[underscore+1:]
+	if dot := strings.Index(locale, "."); dot >= 0 {
+		locale = locale[:dot]
+	}
+	info.locale = locale
+	return info
+}
+
+// bundleFilter selects which release files to keep by platform, CPU
+// architecture and locale. A nil dimension matches everything.
+type bundleFilter struct {
+	os     map[string]bool
+	arch   map[string]bool
+	locale map[string]bool
+}
+
+// newBundleFilter builds a bundleFilter from the comma-separated --os, --arch
+// and --locale flag values. An empty string leaves that dimension unfiltered.
+func newBundleFilter(osFlag, archFlag, localeFlag string) bundleFilter {
+	return bundleFilter{
+		os:     toSet(osFlag),
+		arch:   toSet(archFlag),
+		locale: toSet(localeFlag),
+	}
+}
+
+// toSet splits a comma-separated flag value into a lookup set, returning nil
+// (meaning "unfiltered") for an empty string.
+func toSet(csv string) map[string]bool {
+	if csv == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, v := range strings.Split(csv, ",") {
+		set[strings.TrimSpace(v)] = true
+	}
+	return set
+}
+
+// matches reports whether fileName passes every configured filter dimension.
+// Files that aren't recognized platform bundles, such as the checksum
+// manifest and its signature, always pass.
+func (f bundleFilter) matches(fileName string) bool {
+	info := parseBundleFileName(fileName)
+	if info.os == "" {
+		return true
+	}
+	if f.os != nil && !f.os[info.os] {
+		return false
+	}
+	if f.arch != nil && !f.arch[info.arch] {
+		return false
+	}
+	if f.locale != nil && !f.locale[info.locale] {
+		return false
+	}
+	return true
+}
+
+// selectFiles narrows files down to the ones matching filter.
+func selectFiles(files []string, filter bundleFilter) []string {
+	var out []string
+	for _, f := range files {
+		if filter.matches(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestParseBundleFileName(t *testing.T) {
+	cases := []struct {
+		fileName string
+		want     bundleInfo
+	}{
+		{
+			"tor-browser-linux-x86_64-14.5.1_en-US.tar.xz",
+			bundleInfo{os: "linux", arch: "x86_64", locale: "en-US"},
+		},
+		{
+			"tor-browser-windows-x86_64-14.5.1_en-US.exe",
+			bundleInfo{os: "windows", arch: "x86_64", locale: "en-US"},
+		},
+		{
+			"tor-browser-macos-aarch64-14.5.1_de.dmg",
+			bundleInfo{os: "macos", arch: "aarch64", locale: "de"},
+		},
+		{
+			"tor-browser-android-aarch64-14.5.1.apk",
+			bundleInfo{os: "android", arch: "aarch64", locale: "ALL"},
+		},
+		{
+			"sha256sums-signed-build.txt",
+			bundleInfo{},
+		},
+	}
+
+	for _, c := range cases {
+		got := parseBundleFileName(c.fileName)
+		if got != c.want {
+			t.Errorf("parseBundleFileName(%q) = %+v, want %+v", c.fileName, got, c.want)
+		}
+	}
+}
+
+func TestBundleFilterMatches(t *testing.T) {
+	// A Windows-only filter must not let Windows files through
+	// unconditionally, and must still reject everything else.
+	winOnly := newBundleFilter("windows", "", "")
+	if !winOnly.matches("tor-browser-windows-x86_64-14.5.1_en-US.exe") {
+		t.Error("expected windows bundle to match --os=windows")
+	}
+	if winOnly.matches("tor-browser-linux-x86_64-14.5.1_en-US.tar.xz") {
+		t.Error("expected linux bundle to be rejected by --os=windows")
+	}
+
+	// --os=linux,macos must reject every Windows file too.
+	noWin := newBundleFilter("linux,macos", "", "")
+	if noWin.matches("tor-browser-windows-x86_64-14.5.1_en-US.exe") {
+		t.Error("expected windows bundle to be rejected by --os=linux,macos")
+	}
+
+	// --locale=en-US,ALL must only admit en-US and unlocalized bundles, not
+	// every locale (regression test for the "ALL" short-circuit bug).
+	localeFilter := newBundleFilter("", "", "en-US,ALL")
+	if localeFilter.matches("tor-browser-linux-x86_64-14.5.1_de.tar.xz") {
+		t.Error("expected de locale to be rejected by --locale=en-US,ALL")
+	}
+	if !localeFilter.matches("tor-browser-linux-x86_64-14.5.1_en-US.tar.xz") {
+		t.Error("expected en-US locale to match --locale=en-US,ALL")
+	}
+	if !localeFilter.matches("tor-browser-android-aarch64-14.5.1.apk") {
+		t.Error("expected unlocalized android bundle to match --locale=en-US,ALL")
+	}
+
+	// Non-bundle files (the manifest itself, its signature) always pass.
+	anyFilter := newBundleFilter("linux", "", "")
+	if !anyFilter.matches("sha256sums-signed-build.txt") {
+		t.Error("expected non-bundle files to pass every filter")
+	}
+}
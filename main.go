@@ -9,10 +9,29 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"testing"
 
 	"golang.org/x/net/html"
 )
 
+// remoteContentLength sends a HEAD request for url and returns the
+// Content-Length reported by the server, or 0 if it isn't known.
+func remoteContentLength(url string) (int64, error) {
+	resp, err := http.Head(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Some hosts don't support HEAD; fall back to an unknown size rather
+		// than failing the download outright.
+		return 0, nil
+	}
+	return resp.ContentLength, nil
+}
+
 // fetchHTML downloads the HTML content from the given URL and returns the root HTML node.
 func fetchHTML(url string) *html.Node {
 	// Perform HTTP GET request
@@ -77,7 +96,7 @@ func filterFiles(links []string) []string {
 	return files
 }
 
-func downloadFile(baseURL, fileName, outDir string) error {
+func downloadFile(baseURL, fileName, outDir string, checksums map[string]string) error {
 	// Define allowed extensions inside the function
 	allowedExts := []string{".asc", ".asc-ma1", ".asc-pierov", ".apk", ".bspatch", ".dmg", ".exe", ".gz", ".idsig", ".mar", ".txt", ".zip", ".xz"}
 
@@ -95,79 +114,202 @@ func downloadFile(baseURL, fileName, outDir string) error {
 		return nil
 	}
 
-	// Check if the file already exists
-	outPath := filepath.Join(outDir, fileName)
-	if fileExists(outPath) {
-		log.Printf("File %s already exists, skipping download.\n", outPath)
-		return nil
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", outDir, err)
 	}
 
-	// Download the file
-	// Construct the full URL
 	url := baseURL + fileName
-	resp, err := http.Get(url)
+	outPath := filepath.Join(outDir, fileName)
+
+	// Find out how big the remote file is so we can detect a complete local
+	// copy and resume an incomplete one.
+	remoteSize, err := remoteContentLength(url)
+	if err != nil {
+		return fmt.Errorf("failed to HEAD %s: %v", url, err)
+	}
+
+	var offset int64
+	if info, err := os.Stat(outPath); err == nil && !info.IsDir() {
+		if remoteSize <= 0 {
+			// The server didn't report a Content-Length, so there's no way
+			// to tell a partial file from a complete one. Trust the
+			// existing file rather than resuming into the unknown, which
+			// would otherwise re-download it from scratch on every run.
+			log.Printf("File %s already exists, skipping download (remote size unknown).\n", outPath)
+			return nil
+		}
+		if info.Size() >= remoteSize {
+			log.Printf("File %s already exists, skipping download.\n", outPath)
+			return nil
+		}
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request for %s: %v", url, err)
+	}
+	flags := os.O_CREATE | os.O_WRONLY
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		flags |= os.O_APPEND
+		log.Printf("Resuming %s from byte %d\n", outPath, offset)
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download %s: %v", url, err)
 	}
 	defer resp.Body.Close()
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create directory %s: %v", outDir, err)
+	// A server that ignores Range restarts us from scratch instead of
+	// silently duplicating bytes.
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		offset = 0
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
 	}
 
-	// Create local file
-	outFile, err := os.Create(outPath)
+	outFile, err := os.OpenFile(outPath, flags, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %v", outPath, err)
+		return fmt.Errorf("failed to open file %s: %v", outPath, err)
 	}
 	defer outFile.Close()
 
-	// Copy response body to file
-	_, err = io.Copy(outFile, resp.Body)
+	// Report progress as we go. The digest is checked afterwards, against the
+	// whole file on disk, so a resumed download is hashed correctly too.
+	progress := newProgressWriter(fileName, offset, remoteSize)
+	_, err = io.Copy(outFile, io.TeeReader(resp.Body, progress))
+	progress.done()
 	if err != nil {
 		return fmt.Errorf("error saving %s: %v", outPath, err)
 	}
 
+	if verifyMode == "sha256" || verifyMode == "gpg" {
+		if want, ok := checksums[fileName]; !ok {
+			log.Printf("No checksum manifest entry for %s, skipping verification\n", fileName)
+		} else if err := verifySHA256(outPath, want); err != nil {
+			return err
+		}
+	}
+
 	fmt.Printf("Downloaded %s\n", fileName)
 	return nil
 }
 
-/*
-It checks if the file exists
-If the file exists, it returns true
-If the file does not exist, it returns false
-*/
-func fileExists(filename string) bool {
-	info, err := os.Stat(filename)
-	if err != nil {
-		return false
-	}
-	return !info.IsDir()
-}
-
 var (
 	// Command-line flags
-	torVersion = "14.5.1" // Default Tor Browser version
+	versionSpec = "14.5.1" // "latest", an exact version, or a semver range
+	concurrency = 4        // Number of files to download at once
+	transport   = "http"   // Download backend: "http" or "torrent"
+	torrentURL  = ""       // Optional override for the .torrent file location
+	osFilter    = ""       // Comma-separated --os value, e.g. "linux,windows"
+	archFilter  = ""       // Comma-separated --arch value, e.g. "x86_64"
+	localeFlag  = ""       // Comma-separated --locale value, e.g. "en-US,ALL"
+	extract     = false    // Whether to auto-extract downloaded archives
 )
 
 func init() {
+	if testing.Testing() {
+		// Don't parse the test binary's own -test.* flags as ours.
+		return
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "list", "prune", "gc":
+			subcommand = os.Args[1]
+		}
+	}
+
+	if subcommand != "" {
+		fs := flag.NewFlagSet(subcommand, flag.ExitOnError)
+		dir := fs.String("cache-dir", ".", "Root of the on-disk version cache")
+		keep := fs.Int("keep", 3, "Number of most recent versions to keep per channel (prune only)")
+		fs.Parse(os.Args[2:])
+		cacheDir = *dir
+		pruneKeep = *keep
+		return
+	}
+
 	// Command-line flags
-	version := flag.String("version", "14.5.1", "Tor Browser version to download")
+	version := flag.String("version", "14.5.1", `Tor Browser version to download: "latest", an exact version, or a semver range like ">=14.5,<15"`)
+	ch := flag.String("channel", "stable", "Cache namespace for the downloaded version(s), e.g. stable or alpha")
+	dir := flag.String("cache-dir", ".", "Root of the on-disk version cache")
+	verify := flag.String("verify", "sha256", "Verification to perform on downloaded files: none, sha256, or gpg")
+	keyring := flag.String("keyring", "keys/tor-browser-signing-keys.asc", "Path to an ASCII-armored keyring of Tor Browser release signing keys, fetched out-of-band (required for --verify=gpg)")
+	workers := flag.Int("concurrency", 4, "Number of files to download at the same time")
+	transportFlag := flag.String("transport", "http", "Download backend to use: http or torrent")
+	torrentURLFlag := flag.String("torrent-url", "", "Torrent file to fetch from instead of the default <file>.torrent location (requires --transport=torrent)")
+	osFlag := flag.String("os", "", "Comma-separated platforms to download, e.g. linux,windows,macos,android (default: all)")
+	archFlag := flag.String("arch", "", "Comma-separated CPU architectures to download, e.g. x86_64,aarch64 (default: all)")
+	locale := flag.String("locale", "", `Comma-separated locales to download, e.g. en-US,ALL (default: all)`)
+	extractFlag := flag.Bool("extract", false, "Auto-extract downloaded archives into <cache dir>/extracted/<platform>/")
 	// Parse command-line flags
 	flag.Parse()
 	// Check if version is provided
-	torVersion = *version
-	// Create output directory
-	err := os.MkdirAll(torVersion, 0755)
-	// Check if directory creation was successful
-	if err != nil {
-		log.Fatalln("Failed to create output directory:", err)
+	versionSpec = *version
+	channel = *ch
+	cacheDir = *dir
+	verifyMode = *verify
+	torSigningKeyring = *keyring
+	concurrency = *workers
+	transport = *transportFlag
+	torrentURL = *torrentURLFlag
+	osFilter = *osFlag
+	archFilter = *archFlag
+	localeFlag = *locale
+	extract = *extractFlag
+	if concurrency < 1 {
+		concurrency = 1
 	}
 }
 
 func main() {
-	baseURL := fmt.Sprintf("https://dist.torproject.org/torbrowser/%s/", torVersion)
+	switch subcommand {
+	case "list":
+		runList()
+		return
+	case "prune":
+		if err := pruneCachedVersions(pruneKeep); err != nil {
+			log.Fatalln("Failed to prune cache:", err)
+		}
+		return
+	case "gc":
+		if err := gcCache(); err != nil {
+			log.Fatalln("Failed to collect garbage:", err)
+		}
+		return
+	}
+
+	versions, err := resolveVersions(versionSpec)
+	if err != nil {
+		log.Fatalln("Failed to resolve version spec:", versionSpec, err)
+	}
+	if len(versions) == 0 {
+		log.Fatalln("No versions matched spec:", versionSpec)
+	}
+
+	for _, version := range versions {
+		if isVersionCached(channel, version) {
+			log.Printf("Version %s already mirrored, skipping\n", version)
+			continue
+		}
+		mirrorVersion(version)
+	}
+}
+
+// mirrorVersion downloads every file for version into its cache directory
+// and, on success, marks it complete with the downloaded.ok sentinel.
+func mirrorVersion(version string) {
+	outDir := versionCacheDir(channel, version)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		log.Fatalln("Failed to create output directory:", err)
+	}
+
+	baseURL := fmt.Sprintf("https://dist.torproject.org/torbrowser/%s/", version)
 
 	// Fetch and parse HTML
 	node := fetchHTML(baseURL)
@@ -175,14 +317,69 @@ func main() {
 	// Extract and filter links
 	links := extractLinks(node)
 	files := filterFiles(links)
+	files = selectFiles(files, newBundleFilter(osFilter, archFilter, localeFlag))
 
-	// Download each file
-	for _, file := range files {
-		err := downloadFile(baseURL, file, torVersion)
+	// Fetch the official checksum manifest so each downloaded file can be
+	// cross-referenced against it, verifying the manifest's own signature
+	// first if requested.
+	var checksums map[string]string
+	if verifyMode == "sha256" || verifyMode == "gpg" {
+		var err error
+		checksums, err = obtainChecksumManifest(baseURL, outDir)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+			log.Fatalln("Failed to obtain checksum manifest:", err)
 		}
 	}
 
-	fmt.Printf("All files for version %s have been downloaded into %s/\n", torVersion, torVersion)
+	// Pick the download backend to use for every file this run.
+	var downloader Downloader
+	switch transport {
+	case "torrent":
+		downloader = &TorrentDownloader{torrentURL: torrentURL, baseURL: baseURL, outDir: outDir, checksums: checksums}
+	default:
+		downloader = &HTTPDownloader{baseURL: baseURL, outDir: outDir, checksums: checksums}
+	}
+
+	// Download files using a small worker pool so large release directories
+	// don't have to be fetched one file at a time.
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				if err := downloader.Download(file); err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+				}
+			}
+		}()
+	}
+	for _, file := range files {
+		jobs <- file
+	}
+	close(jobs)
+	wg.Wait()
+
+	if extract {
+		extractDownloadedBundles(files, outDir)
+	}
+
+	if err := markVersionCached(channel, version); err != nil {
+		log.Fatalln("Failed to mark version as mirrored:", err)
+	}
+
+	fmt.Printf("All files for version %s have been downloaded into %s/\n", version, outDir)
+}
+
+// runList prints every fully-mirrored "<channel>/<version>" in cacheDir, for
+// the "list" subcommand.
+func runList() {
+	versions, err := listCachedVersions()
+	if err != nil {
+		log.Fatalln("Failed to list cache:", err)
+	}
+	for _, v := range versions {
+		fmt.Println(v)
+	}
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// progressMu serializes progress lines across the concurrent worker pool so
+// that two goroutines printing at once can't interleave mid-line; with
+// --concurrency workers sharing one terminal there's no single "current
+// line" to redraw in place, so each update is printed as its own line.
+var progressMu sync.Mutex
+
+// progressWriter wraps an io.Copy destination and periodically prints how
+// far a download has gotten, so large multi-GB transfers don't sit silent.
+type progressWriter struct {
+	fileName  string
+	total     int64 // Total size of the file being downloaded, 0 if unknown.
+	written   int64 // Bytes written so far, including any resumed offset.
+	start     time.Time
+	lastPrint time.Time
+}
+
+// newProgressWriter creates a progressWriter for fileName, starting the
+// running total at offset bytes (non-zero when resuming a partial download).
+func newProgressWriter(fileName string, offset, total int64) *progressWriter {
+	return &progressWriter{
+		fileName: fileName,
+		total:    total,
+		written:  offset,
+		start:    time.Now(),
+	}
+}
+
+// Write implements io.Writer, tallying bytes and printing progress no more
+// than a few times a second.
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	p.written += int64(n)
+
+	now := time.Now()
+	if now.Sub(p.lastPrint) < 250*time.Millisecond {
+		return n, nil
+	}
+	p.lastPrint = now
+	p.print(now)
+	return n, nil
+}
+
+// print writes a single progress line for the current state of the transfer.
+// Each call is a complete, newline-terminated line printed under progressMu
+// so concurrent workers' output can't interleave or overwrite one another.
+func (p *progressWriter) print(now time.Time) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	if p.total <= 0 {
+		fmt.Printf("%s: %s\n", p.fileName, formatBytes(p.written))
+		return
+	}
+
+	percent := float64(p.written) / float64(p.total) * 100
+	elapsed := now.Sub(p.start).Seconds()
+	var eta string
+	if rate := float64(p.written) / elapsed; elapsed > 0 && rate > 0 {
+		remaining := float64(p.total-p.written) / rate
+		eta = formatDuration(time.Duration(remaining) * time.Second)
+	} else {
+		eta = "?"
+	}
+
+	fmt.Printf("%s: %s/%s (%.1f%%) ETA %s\n", p.fileName, formatBytes(p.written), formatBytes(p.total), percent, eta)
+}
+
+// done prints a final progress line once a transfer finishes.
+func (p *progressWriter) done() {
+	p.print(time.Now())
+}
+
+// formatBytes renders n bytes as a human-readable size, e.g. "4.2 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration renders d rounded to the nearest second, e.g. "3m12s".
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
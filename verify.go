@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// checksumManifestName is the manifest published alongside every release
+// directory that lists the SHA256 digest of each artifact in it.
+const checksumManifestName = "sha256sums-signed-build.txt"
+
+// checksumManifestSigName is the detached GPG signature for the manifest
+// above, signed by one of the Tor Browser release signing keys.
+const checksumManifestSigName = checksumManifestName + ".asc"
+
+var (
+	// verifyMode controls how downloaded files are checked against the
+	// official dist.torproject.org manifests. One of "none", "sha256", "gpg".
+	verifyMode = "sha256"
+
+	// torSigningKeyring is the path to an ASCII-armored public keyring
+	// containing the known Tor Browser release signing keys (sysrqb, boklm,
+	// pierov, ...). It must be put there out-of-band by the operator (the
+	// --keyring flag points elsewhere if you'd rather keep it outside the
+	// repo) — it is deliberately never fetched from dist.torproject.org at
+	// runtime, since that is the same host this mode exists to authenticate
+	// and a compromised or MITM'd host could simply hand out its own key
+	// alongside its own forged manifest.
+	torSigningKeyring = "keys/tor-browser-signing-keys.asc"
+)
+
+// obtainChecksumManifest downloads sha256sums-signed-build.txt into outDir
+// exactly once, verifies its detached signature against that same file on
+// disk when verifyMode is "gpg", and only then parses checksums out of it.
+// Fetching the manifest a single time, and checking both the signature and
+// the checksums against those same bytes, is what keeps a server from being
+// able to serve one (signed, legitimate) copy to the signature check and a
+// different (tampered) copy to the digest check.
+func obtainChecksumManifest(baseURL, outDir string) (map[string]string, error) {
+	manifestPath := filepath.Join(outDir, checksumManifestName)
+	if err := downloadRaw(baseURL+checksumManifestName, manifestPath); err != nil {
+		return nil, fmt.Errorf("failed to download checksum manifest: %v", err)
+	}
+
+	if verifyMode == "gpg" {
+		if err := verifyManifestSignature(baseURL, outDir, manifestPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return parseChecksumManifest(manifestPath)
+}
+
+// parseChecksumManifest reads a local sha256sums-signed-build.txt and parses
+// its "<hex digest>  <filename>" lines into a lookup map.
+func parseChecksumManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checksum manifest %s: %v", path, err)
+	}
+	defer f.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			// Skip blank lines or anything that isn't "<digest>  <file>".
+			continue
+		}
+		sums[fields[1]] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksum manifest: %v", err)
+	}
+	return sums, nil
+}
+
+// verifyManifestSignature downloads the detached signature for manifestPath
+// and shells out to gpg to confirm manifestPath itself (already on disk) was
+// signed by a key in torSigningKeyring.
+func verifyManifestSignature(baseURL, outDir, manifestPath string) error {
+	if _, err := os.Stat(torSigningKeyring); err != nil {
+		return fmt.Errorf("signing keyring %s not found: fetch the Tor Browser release signing keys out-of-band and place them there (or pass --keyring), then retry: %v", torSigningKeyring, err)
+	}
+
+	keyring, err := dearmoredKeyring(torSigningKeyring)
+	if err != nil {
+		return err
+	}
+
+	sigPath := filepath.Join(outDir, checksumManifestSigName)
+	if err := downloadRaw(baseURL+checksumManifestSigName, sigPath); err != nil {
+		return fmt.Errorf("failed to download manifest signature: %v", err)
+	}
+
+	cmd := exec.Command("gpg", "--no-default-keyring", "--keyring", keyring, "--verify", sigPath, manifestPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gpg signature verification failed for %s: %v", manifestPath, err)
+	}
+	return nil
+}
+
+// dearmoredKeyring converts the ASCII-armored keyring at armoredPath into the
+// binary keybox format "gpg --keyring" actually requires (gpg rejects an
+// armored file passed that way with "invalid packet"), caching the result
+// next to it and re-dearmoring only when the source file is newer.
+func dearmoredKeyring(armoredPath string) (string, error) {
+	binPath := armoredPath + ".gpg"
+
+	armoredInfo, err := os.Stat(armoredPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat keyring %s: %v", armoredPath, err)
+	}
+	if binInfo, err := os.Stat(binPath); err == nil && binInfo.ModTime().After(armoredInfo.ModTime()) {
+		return binPath, nil
+	}
+
+	cmd := exec.Command("gpg", "--yes", "--dearmor", "--output", binPath, armoredPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to dearmor keyring %s: %v", armoredPath, err)
+	}
+	return binPath, nil
+}
+
+// downloadRaw fetches url and writes the response body to outPath verbatim,
+// without the extension allow-list or skip-if-exists checks downloadFile applies.
+func downloadRaw(url, outPath string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %v", outPath, err)
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// verifySHA256 hashes outPath and compares the result against want, a
+// lowercase hex digest. The file is deleted if the digests do not match.
+func verifySHA256(outPath, want string) error {
+	f, err := os.Open(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for verification: %v", outPath, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash %s: %v", outPath, err)
+	}
+	got := hex.EncodeToString(h.Sum(nil))
+
+	if got != want {
+		os.Remove(outPath)
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", outPath, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestDearmoredKeyringVerifiesRealSignature proves the gpg --verify flow end
+// to end with a throwaway key pair: an ASCII-armored keyring, dearmored by
+// dearmoredKeyring, must let a real `gpg --verify` accept a real signature.
+// Before dearmoredKeyring existed, passing the armored file straight to
+// `gpg --keyring` failed on every run with "invalid packet (ctb=2d)".
+func TestDearmoredKeyringVerifiesRealSignature(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GNUPGHOME", filepath.Join(dir, "gnupghome"))
+	if err := os.Mkdir(os.Getenv("GNUPGHOME"), 0700); err != nil {
+		t.Fatalf("failed to create GNUPGHOME: %v", err)
+	}
+
+	batchFile := filepath.Join(dir, "keygen.batch")
+	batch := "%no-protection\nKey-Type: RSA\nKey-Length: 2048\nName-Real: Test Signer\nName-Email: test@example.com\nExpire-Date: 0\n%commit\n"
+	if err := os.WriteFile(batchFile, []byte(batch), 0600); err != nil {
+		t.Fatalf("failed to write key batch file: %v", err)
+	}
+	if out, err := exec.Command("gpg", "--batch", "--gen-key", batchFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate test key: %v\n%s", err, out)
+	}
+
+	armoredKeyring := filepath.Join(dir, "keyring.asc")
+	exportCmd := exec.Command("gpg", "--export", "--armor", "test@example.com")
+	exported, err := exportCmd.Output()
+	if err != nil {
+		t.Fatalf("failed to export test public key: %v", err)
+	}
+	if err := os.WriteFile(armoredKeyring, exported, 0644); err != nil {
+		t.Fatalf("failed to write armored keyring: %v", err)
+	}
+
+	manifestPath := filepath.Join(dir, checksumManifestName)
+	if err := os.WriteFile(manifestPath, []byte("deadbeef  tor-browser-linux-x86_64-14.5.1.tar.xz\n"), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	sigPath := manifestPath + ".asc"
+	if out, err := exec.Command("gpg", "--batch", "--yes", "--detach-sign", "--armor", "-o", sigPath, manifestPath).CombinedOutput(); err != nil {
+		t.Fatalf("failed to sign test manifest: %v\n%s", err, out)
+	}
+
+	keyring, err := dearmoredKeyring(armoredKeyring)
+	if err != nil {
+		t.Fatalf("dearmoredKeyring failed: %v", err)
+	}
+
+	verifyCmd := exec.Command("gpg", "--no-default-keyring", "--keyring", keyring, "--verify", sigPath, manifestPath)
+	if out, err := verifyCmd.CombinedOutput(); err != nil {
+		t.Fatalf("gpg --verify against the dearmored keyring failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// distIndexURL lists every Tor Browser version ever published.
+const distIndexURL = "https://dist.torproject.org/torbrowser/"
+
+// downloadedSentinel marks a cache directory as a complete mirror of a
+// version, mirroring the unpackedOkay pattern golang.org/dl uses for its
+// own Go toolchain cache.
+const downloadedSentinel = "downloaded.ok"
+
+var (
+	// cacheDir is the root of the on-disk mirror tree:
+	// <cacheDir>/<channel>/<version>/<files>.
+	cacheDir = "."
+
+	// channel namespaces cached versions, e.g. "stable" or "alpha".
+	channel = "stable"
+
+	// subcommand is set by init() when os.Args[1] is "list", "prune", or
+	// "gc", and short-circuits main()'s usual download flow.
+	subcommand = ""
+
+	// pruneKeep is the --keep value for the "prune" subcommand.
+	pruneKeep = 3
+)
+
+// versionCacheDir returns the on-disk directory a version's files live in.
+func versionCacheDir(channel, version string) string {
+	return filepath.Join(cacheDir, channel, version)
+}
+
+// isVersionCached reports whether version already has a complete mirror.
+func isVersionCached(channel, version string) bool {
+	_, err := os.Stat(filepath.Join(versionCacheDir(channel, version), downloadedSentinel))
+	return err == nil
+}
+
+// markVersionCached writes the sentinel file that marks version complete.
+func markVersionCached(channel, version string) error {
+	path := filepath.Join(versionCacheDir(channel, version), downloadedSentinel)
+	return os.WriteFile(path, nil, 0644)
+}
+
+// listRemoteVersions scrapes the top-level torbrowser directory listing and
+// returns every published version, newest first.
+func listRemoteVersions() ([]string, error) {
+	node := fetchHTML(distIndexURL)
+	if node == nil {
+		return nil, fmt.Errorf("failed to list %s", distIndexURL)
+	}
+
+	var versions []string
+	for _, link := range extractLinks(node) {
+		if !strings.HasSuffix(link, "/") {
+			continue
+		}
+		v := strings.TrimSuffix(link, "/")
+		if !semver.IsValid(canonicalSemver(v)) {
+			log.Printf("Skipping unparseable version directory %q\n", v)
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare(canonicalSemver(versions[i]), canonicalSemver(versions[j])) > 0
+	})
+	return versions, nil
+}
+
+// resolveVersions expands a --version spec into the concrete versions it
+// matches: "latest" resolves to the newest published version, an exact
+// version such as "14.5.1" resolves to itself, and a comma-separated range
+// such as ">=14.5,<15" resolves to every matching published version.
+func resolveVersions(spec string) ([]string, error) {
+	if spec == "latest" {
+		all, err := listRemoteVersions()
+		if err != nil {
+			return nil, err
+		}
+		if len(all) == 0 {
+			return nil, fmt.Errorf("no versions found at %s", distIndexURL)
+		}
+		return all[:1], nil
+	}
+
+	if !strings.ContainsAny(spec, "<>=,") {
+		return []string{spec}, nil
+	}
+
+	constraints, err := parseSemverRange(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := listRemoteVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []string
+	for _, v := range all {
+		if constraints.matches(v) {
+			matched = append(matched, v)
+		}
+	}
+	return matched, nil
+}
+
+// semverRange is a set of ANDed comparator constraints, e.g. ">=14.5,<15".
+type semverRange []semverConstraint
+
+type semverConstraint struct {
+	op      string // One of "=", ">", ">=", "<", "<=".
+	version string // Canonical semver, with a leading "v".
+}
+
+// parseSemverRange parses a comma-separated list of comparator constraints.
+func parseSemverRange(spec string) (semverRange, error) {
+	var r semverRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		op, ver := splitConstraint(part)
+		ver = canonicalSemver(ver)
+		if !semver.IsValid(ver) {
+			return nil, fmt.Errorf("invalid version constraint %q", part)
+		}
+		r = append(r, semverConstraint{op: op, version: ver})
+	}
+	return r, nil
+}
+
+// splitConstraint separates a leading comparator from the version that
+// follows it, defaulting to "=" when the constraint has none.
+func splitConstraint(part string) (op, version string) {
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(part, candidate) {
+			return candidate, strings.TrimSpace(strings.TrimPrefix(part, candidate))
+		}
+	}
+	return "=", part
+}
+
+// alphaSuffixPattern matches a version's numeric core followed directly by a
+// prerelease tag with no separating hyphen, e.g. the "a7" in "13.5a7" (Tor
+// Browser's alpha/hardened releases use this style). The optional third
+// group captures the patch component so we can tell whether one is present.
+var alphaSuffixPattern = regexp.MustCompile(`^(\d+\.\d+)(\.\d+)?([a-zA-Z].*)$`)
+
+// canonicalSemver adds the "v" prefix golang.org/x/mod/semver requires and
+// inserts the hyphen (and, if missing, the patch component) it demands before
+// a prerelease tag. Tor Browser versions such as "13.5a7" would otherwise be
+// rejected by semver.IsValid outright, silently dropping every alpha/hardened
+// release from "latest" and range resolution.
+func canonicalSemver(v string) string {
+	v = strings.TrimPrefix(v, "v")
+	if m := alphaSuffixPattern.FindStringSubmatch(v); m != nil {
+		patch := m[2]
+		if patch == "" {
+			patch = ".0"
+		}
+		v = m[1] + patch + "-" + m[3]
+	}
+	return "v" + v
+}
+
+// matches reports whether version satisfies every constraint in the range.
+func (r semverRange) matches(version string) bool {
+	cv := canonicalSemver(version)
+	for _, c := range r {
+		cmp := semver.Compare(cv, c.version)
+		switch c.op {
+		case "=":
+			if cmp != 0 {
+				return false
+			}
+		case ">":
+			if cmp <= 0 {
+				return false
+			}
+		case ">=":
+			if cmp < 0 {
+				return false
+			}
+		case "<":
+			if cmp >= 0 {
+				return false
+			}
+		case "<=":
+			if cmp > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// listCachedVersions returns every "<channel>/<version>" pair in cacheDir
+// with a downloaded.ok sentinel, for the "list" subcommand.
+func listCachedVersions() ([]string, error) {
+	channels, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var out []string
+	for _, ch := range channels {
+		if !ch.IsDir() {
+			continue
+		}
+		versions, err := os.ReadDir(filepath.Join(cacheDir, ch.Name()))
+		if err != nil {
+			continue
+		}
+		for _, v := range versions {
+			if v.IsDir() && isVersionCached(ch.Name(), v.Name()) {
+				out = append(out, filepath.Join(ch.Name(), v.Name()))
+			}
+		}
+	}
+	return out, nil
+}
+
+// pruneCachedVersions removes every cached version beyond the keep newest
+// per channel, for the "prune --keep=N" subcommand.
+func pruneCachedVersions(keep int) error {
+	channels, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, ch := range channels {
+		if !ch.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(cacheDir, ch.Name()))
+		if err != nil {
+			continue
+		}
+
+		var versions []string
+		for _, e := range entries {
+			if e.IsDir() {
+				versions = append(versions, e.Name())
+			}
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			return semver.Compare(canonicalSemver(versions[i]), canonicalSemver(versions[j])) > 0
+		})
+
+		for _, v := range versions[intMin(keep, len(versions)):] {
+			dir := versionCacheDir(ch.Name(), v)
+			log.Printf("Pruning %s\n", dir)
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to prune %s: %v", dir, err)
+			}
+		}
+	}
+	return nil
+}
+
+// gcCache removes any cache directory left behind by an interrupted run,
+// i.e. one with no downloaded.ok sentinel.
+func gcCache() error {
+	channels, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, ch := range channels {
+		if !ch.IsDir() {
+			continue
+		}
+		versions, err := os.ReadDir(filepath.Join(cacheDir, ch.Name()))
+		if err != nil {
+			continue
+		}
+		for _, v := range versions {
+			if !v.IsDir() || isVersionCached(ch.Name(), v.Name()) {
+				continue
+			}
+			dir := versionCacheDir(ch.Name(), v.Name())
+			log.Printf("Removing incomplete mirror %s\n", dir)
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to remove %s: %v", dir, err)
+			}
+		}
+	}
+	return nil
+}
+
+// intMin returns the smaller of a and b.
+func intMin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
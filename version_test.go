@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/mod/semver"
+)
+
+func TestCanonicalSemver(t *testing.T) {
+	cases := map[string]string{
+		"14.5.1":   "v14.5.1",
+		"14.5":     "v14.5",
+		"13.5a7":   "v13.5.0-a7",
+		"13.5.1a7": "v13.5.1-a7",
+		"v14.5.1":  "v14.5.1",
+	}
+	for in, want := range cases {
+		if got := canonicalSemver(in); got != want {
+			t.Errorf("canonicalSemver(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCanonicalSemverAcceptsAlphaVersions(t *testing.T) {
+	for _, v := range []string{"13.5a7", "14.0.9a1"} {
+		if !semver.IsValid(canonicalSemver(v)) {
+			t.Errorf("expected %q to be a valid version after canonicalization", v)
+		}
+	}
+}
+
+func TestSemverRangeMatchesAlphaVersions(t *testing.T) {
+	r, err := parseSemverRange(">=13.5a1,<14")
+	if err != nil {
+		t.Fatalf("parseSemverRange failed: %v", err)
+	}
+	if !r.matches("13.5a7") {
+		t.Error("expected 13.5a7 to match >=13.5a1,<14")
+	}
+	if r.matches("12.9a1") {
+		t.Error("expected 12.9a1 to be excluded by >=13.5a1,<14")
+	}
+}